@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// containerKind distinguishes a pod's regular containers from its init and ephemeral ones, since
+// each needs its own filename suffix and previous-log lookup.
+type containerKind string
+
+const (
+	containerKindApp       containerKind = ""
+	containerKindInit      containerKind = "init"
+	containerKindEphemeral containerKind = "ephemeral"
+)
+
+// podContainer is one container (of any kind) belonging to a pod, as selected for log collection.
+type podContainer struct {
+	name string
+	kind containerKind
+}
+
+// logName is the sink identifier used to build per-container file/object names, e.g. "app-init"
+// for an init container named "app", so it never collides with the app container "app".
+func (c podContainer) logName() string {
+	if c.kind == containerKindApp {
+		return c.name
+	}
+	return fmt.Sprintf("%s-%s", c.name, c.kind)
+}
+
+// displayName is what's shown in the interactive pod/container tree.
+func (c podContainer) displayName() string {
+	if c.kind == containerKindApp {
+		return c.name
+	}
+	return fmt.Sprintf("%s (%s)", c.name, c.kind)
+}
+
+// podContainers lists every container klogs can fetch logs for: the regular spec containers,
+// the init containers, and any ephemeral containers that have actually been started.
+func podContainers(pod v1.Pod) []podContainer {
+	var containers []podContainer
+	for _, c := range pod.Spec.Containers {
+		containers = append(containers, podContainer{name: c.Name, kind: containerKindApp})
+	}
+	for _, c := range pod.Spec.InitContainers {
+		containers = append(containers, podContainer{name: c.Name, kind: containerKindInit})
+	}
+	for _, c := range pod.Status.EphemeralContainerStatuses {
+		containers = append(containers, podContainer{name: c.Name, kind: containerKindEphemeral})
+	}
+	return containers
+}
+
+// containerLastTerminated reports whether pc has a prior termination to fetch with
+// PodLogOptions.Previous, so --previous can skip containers that never crashed.
+func containerLastTerminated(pod v1.Pod, pc podContainer) bool {
+	var statuses []v1.ContainerStatus
+	switch pc.kind {
+	case containerKindInit:
+		statuses = pod.Status.InitContainerStatuses
+	case containerKindEphemeral:
+		statuses = pod.Status.EphemeralContainerStatuses
+	default:
+		statuses = pod.Status.ContainerStatuses
+	}
+
+	for _, status := range statuses {
+		if status.Name == pc.name {
+			return status.LastTerminationState.Terminated != nil
+		}
+	}
+	return false
+}
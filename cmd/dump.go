@@ -0,0 +1,305 @@
+/*
+Package cmd: dump subcommand for capturing full diagnostic bundles.
+*/
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	dumpAllNamespaces  *bool
+	dumpNamespaces     *[]string
+	dumpOutDir         *string
+	dumpPodLogsTimeout *time.Duration
+)
+
+var dumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Capture a full diagnostic bundle for one or more namespaces",
+	Long: `dump mirrors kubectl's cluster-info dump: for each selected namespace it saves logs for every
+app, init and ephemeral container of every pod, alongside serialized manifests for Pods, Deployments,
+ReplicaSets, DaemonSets, StatefulSets, Services, Events and ConfigMaps, laid out as
+<outdir>/<namespace>/<kind>/<name>.yaml and <outdir>/<namespace>/<pod>/<container>.log (plus
+<pod>/previous/<container>.log for crashed containers), so the bundle can be handed off as a
+self-contained incident-response artifact.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		if err := configClient(ctx); err != nil {
+			return err
+		}
+
+		namespaces, err := resolveDumpNamespaces(ctx)
+		if err != nil {
+			return err
+		}
+		if len(namespaces) == 0 {
+			pterm.Error.Printfln("No namespaces to dump")
+			return nil
+		}
+
+		for _, ns := range namespaces {
+			if err := dumpNamespace(ctx, ns); err != nil {
+				return err
+			}
+		}
+
+		pterm.Info.Printfln("Diagnostic bundle written to %s", pterm.Green(*dumpOutDir))
+		return nil
+	},
+}
+
+func resolveDumpNamespaces(ctx context.Context) ([]string, error) {
+	if *dumpAllNamespaces {
+		list, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, n := range list.Items {
+			names = append(names, n.Name)
+		}
+		return names, nil
+	}
+	if len(*dumpNamespaces) > 0 {
+		return *dumpNamespaces, nil
+	}
+	ns, err := getCurrentNamespace(*kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return []string{ns}, nil
+}
+
+func dumpNamespace(ctx context.Context, ns string) error {
+	pterm.Info.Printfln("Dumping namespace %s", pterm.Green(ns))
+
+	nsDir := filepath.Join(*dumpOutDir, ns)
+
+	pods, err := dumpPods(ctx, ns, nsDir)
+	if err != nil {
+		return err
+	}
+	if err := dumpDeployments(ctx, ns, nsDir); err != nil {
+		return err
+	}
+	if err := dumpReplicaSets(ctx, ns, nsDir); err != nil {
+		return err
+	}
+	if err := dumpDaemonSets(ctx, ns, nsDir); err != nil {
+		return err
+	}
+	if err := dumpStatefulSets(ctx, ns, nsDir); err != nil {
+		return err
+	}
+	if err := dumpServices(ctx, ns, nsDir); err != nil {
+		return err
+	}
+	if err := dumpEvents(ctx, ns, nsDir); err != nil {
+		return err
+	}
+	if err := dumpConfigMaps(ctx, ns, nsDir); err != nil {
+		return err
+	}
+
+	for _, pod := range pods {
+		if err := dumpPodLogs(ctx, ns, pod, nsDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dumpManifest marshals obj as YAML into <dir>/<kind>/<name>.yaml.
+func dumpManifest(dir, kind, name string, obj interface{}) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		pterm.Error.Printfln("Error marshaling %s %s: %v", kind, name, err)
+		return nil
+	}
+
+	kindDir := filepath.Join(dir, kind)
+	if err := os.MkdirAll(kindDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(kindDir, name+".yaml"), data, 0644)
+}
+
+func dumpPods(ctx context.Context, ns, dir string) ([]v1.Pod, error) {
+	pods, err := client.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		if err := dumpManifest(dir, "Pod", pod.Name, pod); err != nil {
+			return nil, err
+		}
+	}
+	return pods.Items, nil
+}
+
+func dumpDeployments(ctx context.Context, ns, dir string) error {
+	deployments, err := client.AppsV1().Deployments(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, d := range deployments.Items {
+		if err := dumpManifest(dir, "Deployment", d.Name, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dumpReplicaSets(ctx context.Context, ns, dir string) error {
+	replicaSets, err := client.AppsV1().ReplicaSets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, rs := range replicaSets.Items {
+		if err := dumpManifest(dir, "ReplicaSet", rs.Name, rs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dumpDaemonSets(ctx context.Context, ns, dir string) error {
+	daemonSets, err := client.AppsV1().DaemonSets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, ds := range daemonSets.Items {
+		if err := dumpManifest(dir, "DaemonSet", ds.Name, ds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dumpStatefulSets(ctx context.Context, ns, dir string) error {
+	statefulSets, err := client.AppsV1().StatefulSets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, ss := range statefulSets.Items {
+		if err := dumpManifest(dir, "StatefulSet", ss.Name, ss); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dumpServices(ctx context.Context, ns, dir string) error {
+	services, err := client.CoreV1().Services(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, svc := range services.Items {
+		if err := dumpManifest(dir, "Service", svc.Name, svc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dumpEvents(ctx context.Context, ns, dir string) error {
+	events, err := client.CoreV1().Events(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, ev := range events.Items {
+		if err := dumpManifest(dir, "Event", ev.Name, ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dumpConfigMaps(ctx context.Context, ns, dir string) error {
+	configMaps, err := client.CoreV1().ConfigMaps(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, cm := range configMaps.Items {
+		if err := dumpManifest(dir, "ConfigMap", cm.Name, cm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dumpPodLogs saves current (and, for crashed containers, previous) logs for every app, init and
+// ephemeral container in pod, under <dir>/<pod>/<logName>.log and <dir>/<pod>/previous/<logName>.log,
+// using the same container set and previous-termination check as the interactive command so the
+// bundle is just as useful for debugging CrashLoopBackOff pods.
+func dumpPodLogs(ctx context.Context, ns string, pod v1.Pod, dir string) error {
+	podDir := filepath.Join(dir, pod.Name)
+	if err := os.MkdirAll(podDir, 0755); err != nil {
+		return err
+	}
+
+	for _, pc := range podContainers(pod) {
+		if err := dumpContainerLog(ctx, ns, pod.Name, pc, podDir, false); err != nil {
+			return err
+		}
+
+		if containerLastTerminated(pod, pc) {
+			previousDir := filepath.Join(podDir, "previous")
+			if err := os.MkdirAll(previousDir, 0755); err != nil {
+				return err
+			}
+			if err := dumpContainerLog(ctx, ns, pod.Name, pc, previousDir, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func dumpContainerLog(ctx context.Context, ns, podName string, pc podContainer, dir string, previous bool) error {
+	streamCtx, cancel := context.WithTimeout(ctx, *dumpPodLogsTimeout)
+	defer cancel()
+
+	req := client.CoreV1().Pods(ns).GetLogs(podName, &v1.PodLogOptions{
+		Container: pc.name,
+		Previous:  previous,
+	})
+
+	logs, err := req.Stream(streamCtx)
+	if err != nil {
+		pterm.Warning.Printfln("Error getting logs for %s/%s: %v", podName, pc.logName(), err)
+		return nil
+	}
+	defer logs.Close()
+
+	logFile, err := os.Create(filepath.Join(dir, pc.logName()+".log"))
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	if _, err := logFile.ReadFrom(logs); err != nil {
+		pterm.Warning.Printfln("Error writing logs for %s/%s: %v", podName, pc.logName(), err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(dumpCmd)
+
+	dumpAllNamespaces = dumpCmd.Flags().BoolP("all-namespaces", "A", false, "Dump all namespaces")
+	dumpNamespaces = dumpCmd.Flags().StringArray("namespaces", []string{}, "Namespaces to dump (defaults to the current kubeconfig namespace)")
+	dumpOutDir = dumpCmd.Flags().StringP("outdir", "o", "dump/"+time.Now().Format("2006-01-02T15:04"), "Directory the diagnostic bundle is written to")
+	dumpPodLogsTimeout = dumpCmd.Flags().Duration("pod-logs-timeout", 30*time.Second, "Timeout for each pod log stream")
+}
@@ -6,11 +6,16 @@ package cmd
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"regexp"
 	"sync"
+	"syscall"
 	"time"
 
 	"atomicgo.dev/keyboard/keys"
@@ -18,7 +23,6 @@ import (
 	"github.com/pterm/pterm/putils"
 	"github.com/spf13/cobra"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
@@ -35,6 +39,11 @@ var (
 	labels                                *[]string
 	tail                                  *int64
 	follow                                *bool
+	stdout, noSave, timestamps            *bool
+	previous                              *bool
+	sink, lokiURL                         *string
+	fieldSelector, containerRegex         *string
+	allNamespaces, readyOnly              *bool
 )
 
 var (
@@ -57,41 +66,46 @@ func splashScreen() {
 	pterm.DefaultParagraph.Printfln("Version: %s", BuildVersion)
 }
 
-func configClient() {
+func configClient(ctx context.Context) error {
 
 	// use the current context in kubeconfig
 	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 
 	// create the client
 	client, err = kubernetes.NewForConfig(config)
-	if err != nil {
-		panic(err.Error())
-	}
+	return err
 }
 
-func configNamespace() {
+func configNamespace(ctx context.Context) error {
 	if *namespace == "" {
-		*namespace = getCurrentNamespace(*kubeconfig)
+		ns, err := getCurrentNamespace(*kubeconfig)
+		if err != nil {
+			return err
+		}
+		*namespace = ns
 	}
 
 	// check if namespace exists
-	_, err := client.CoreV1().Namespaces().Get(context.TODO(), *namespace, metav1.GetOptions{})
+	_, err := client.CoreV1().Namespaces().Get(ctx, *namespace, metav1.GetOptions{})
 	if err != nil {
 		pterm.Warning.Printfln("Namespace %s not found", *namespace)
-		listNamespaces()
+		if err := listNamespaces(ctx); err != nil {
+			return err
+		}
 	}
 
 	pterm.Info.Printfln("Using Namespace: %s", pterm.Green(*namespace))
+	return nil
 }
 
-func listNamespaces() {
+func listNamespaces(ctx context.Context) error {
 	// get namespaces and prompt user to select one
-	namespaces, err := client.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	namespaces, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 	var ns []string
 	for _, n := range namespaces.Items {
@@ -104,46 +118,7 @@ func listNamespaces() {
 		WithOptions(ns).
 		WithDefaultText("Select a Namespace").
 		Show()
-}
-
-func listAllPods() v1.PodList {
-	var _podList v1.PodList
-	pods, err := client.CoreV1().Pods(*namespace).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		panic(err.Error())
-	}
-
-	var podMap = make(map[string]v1.Pod)
-	var podNames []string
-	for _, pod := range pods.Items {
-		// is the pod ready?
-		for _, condition := range pod.Status.Conditions {
-			if condition.Type == v1.PodReady && condition.Status == v1.ConditionTrue {
-				podMap[pod.Name] = pod
-				podNames = append(podNames, pod.Name)
-				break
-			}
-		}
-	}
-
-	if len(podNames) == 0 {
-		pterm.Error.Printfln("No pods found in namespace %s", *namespace)
-		return _podList
-	}
-
-	if !*allPods {
-		podNames = showInteractivePodSelect(podNames)
-		if len(podNames) == 0 {
-			pterm.Error.Printfln("No pods selected")
-			return _podList
-		}
-	}
-
-	// collect info only for the selected pods
-	for _, podName := range podNames {
-		_podList.Items = append(_podList.Items, podMap[podName])
-	}
-	return _podList
+	return nil
 }
 
 func showInteractivePodSelect(podNames []string) []string {
@@ -164,11 +139,11 @@ func showInteractivePodSelect(podNames []string) []string {
 }
 
 // Get the default namespace specified in the KUBECONFIG file current context
-func getCurrentNamespace(kubeconfig string) string {
+func getCurrentNamespace(kubeconfig string) (string, error) {
 
 	config, err := clientcmd.LoadFromFile(kubeconfig)
 	if err != nil {
-		panic(err.Error())
+		return "", err
 	}
 	ns := config.Contexts[config.CurrentContext].Namespace
 
@@ -176,19 +151,34 @@ func getCurrentNamespace(kubeconfig string) string {
 		ns = "default"
 	}
 
-	return ns
+	return ns, nil
 }
 
-func getLopOpts() v1.PodLogOptions {
+// getLopOpts builds the PodLogOptions for the request, plus a sinceCutoff for client-side
+// filtering. SinceSeconds only has whole-second granularity; when --timestamps is also set we
+// round it up to the next whole second so the server-side window is always a superset of
+// sinceCutoff, then parse each line's leading RFC3339 token and drop anything older than
+// sinceCutoff, giving --since sub-second precision. sinceCutoff is the zero time.Time when that
+// filtering doesn't apply.
+func getLopOpts() (v1.PodLogOptions, time.Time, error) {
 	var logOpts v1.PodLogOptions
+	var sinceCutoff time.Time
 	// Since
 	if *since != "" {
 		// After
 		duration, err := time.ParseDuration(*since)
 		if err != nil {
-			panic(err.Error())
+			return logOpts, sinceCutoff, err
 		}
 		s := int64(duration.Seconds())
+		if *timestamps {
+			// Round up so the server-side window is always a superset of sinceCutoff; the
+			// timestampFilterWriter then trims it back down to sub-second precision. Rounding
+			// down here would ask the API for less than sinceCutoff covers, and a line dropped
+			// by SinceSeconds can never be recovered by a filter that only removes lines.
+			s = int64(math.Ceil(duration.Seconds()))
+			sinceCutoff = time.Now().Add(-duration)
+		}
 		logOpts.SinceSeconds = &s
 	}
 	// Tail
@@ -197,153 +187,239 @@ func getLopOpts() v1.PodLogOptions {
 	}
 	// Follow
 	logOpts.Follow = *follow
+	// Timestamps
+	logOpts.Timestamps = *timestamps
 
-	return logOpts
+	return logOpts, sinceCutoff, nil
 }
 
-func getPodLogs(pods v1.PodList, logOpts v1.PodLogOptions) {
+// logSummary reports how a single container's stream ended, for the end-of-run summary.
+type logSummary struct {
+	pod       string
+	container string
+	written   int
+	err       error
+}
+
+func getPodLogs(ctx context.Context, pods []namespacedPod, logOpts v1.PodLogOptions, containerFilter *regexp.Regexp, sinceCutoff time.Time) error {
 	var wg sync.WaitGroup
+	summaries := make(chan logSummary)
+	collected := collectLogSummaries(summaries)
+
+	sinks, err := resolveSinks(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Create a multi printer for managing multiple printers
 	multiPrinter := pterm.DefaultMultiPrinter
 	multiPrinter.Start()
 
-	for _, pod := range pods.Items {
+	for _, np := range pods {
 		var _podTree = pterm.TreeNode{
 			Text: pterm.Info.
 				WithPrefix(pterm.Prefix{Text: "[Pod]", Style: pterm.Info.MessageStyle}).
 				WithMessageStyle(pterm.DefaultBasicText.Style).
-				Sprintf(pod.Name),
+				Sprintf(np.key()),
 		}
 		var containerTree []pterm.TreeNode
 
-		for _, container := range pod.Spec.Containers {
-			containerTree = append(containerTree, pterm.TreeNode{Text: container.Name})
+		for _, pc := range filterContainers(podContainers(np.pod), containerFilter) {
+			containerTree = append(containerTree, pterm.TreeNode{Text: pc.displayName()})
 			_podTree.Children = containerTree
 
 			wg.Add(1)
-			go streamLog(pod, container, logOpts, &wg, &multiPrinter)
+			go streamLog(ctx, np, pc, logOpts, false, &wg, &multiPrinter, summaries, sinks, sinceCutoff)
+
+			if *previous && containerLastTerminated(np.pod, pc) {
+				wg.Add(1)
+				go streamLog(ctx, np, pc, logOpts, true, &wg, &multiPrinter, summaries, sinks, sinceCutoff)
+			}
 		}
 		err := pterm.DefaultTree.WithRoot(_podTree).Render()
 		if err != nil {
-			return
+			return err
 		}
 	}
 	if *follow {
 		pterm.Info.Printfln("Press %s to stop streaming logs.", pterm.Green("Ctrl+C"))
 	}
 
-	// wait for all goroutines to finish
+	// wait for all goroutines to finish, then report what each one wrote
 	wg.Wait()
+	close(summaries)
+	printLogSummary(<-collected)
+
+	return nil
+}
+
+// collectLogSummaries drains summaries as they arrive, so a fan-out larger than any fixed
+// buffer (many containers, --previous doubling some of them) can never deadlock on send.
+func collectLogSummaries(summaries <-chan logSummary) <-chan []logSummary {
+	collected := make(chan []logSummary, 1)
+	go func() {
+		var all []logSummary
+		for s := range summaries {
+			all = append(all, s)
+		}
+		collected <- all
+	}()
+	return collected
+}
+
+func printLogSummary(summaries []logSummary) {
+	pterm.Info.Println("Summary:")
+	for _, s := range summaries {
+		switch {
+		case s.err != nil && s.written == 0:
+			pterm.Error.Printfln("  %s/%s: %v", s.pod, s.container, s.err)
+		case s.err != nil:
+			pterm.Warning.Printfln("  %s/%s:%s (stopped: %v)", s.pod, s.container, convertBytes(s.written), s.err)
+		default:
+			pterm.Println(pterm.Sprintf("  %s/%s:%s", s.pod, s.container, convertBytes(s.written)))
+		}
+	}
 }
 
-func streamLog(pod v1.Pod, container v1.Container, logOpts v1.PodLogOptions, wg *sync.WaitGroup, multiPrinter *pterm.MultiPrinter) {
+func streamLog(ctx context.Context, np namespacedPod, pc podContainer, logOpts v1.PodLogOptions, previous bool, wg *sync.WaitGroup, multiPrinter *pterm.MultiPrinter, summaries chan<- logSummary, sinks []LogSink, sinceCutoff time.Time) {
 	defer wg.Done()
 
-	logOpts.Container = container.Name
+	logOpts.Container = pc.name
+	logOpts.Previous = previous
+
+	logID := pc.logName()
+	if previous {
+		logID += ".previous"
+	}
+	podName := np.logPodName()
+
 	// get logs for the container
-	req := client.CoreV1().Pods(*namespace).GetLogs(pod.Name, &logOpts)
+	req := client.CoreV1().Pods(np.namespace).GetLogs(np.pod.Name, &logOpts)
 
 	// get logs
-	logs, err := req.Stream(context.Background())
+	logs, err := req.Stream(ctx)
 	if err != nil {
-		pterm.Error.Printfln("Error getting logs for container %s\n%v", container.Name, err)
-		//containerTree = append(containerTree, pterm.TreeNode{Text: pterm.Red(container.Name)})
+		pterm.Error.Printfln("Error getting logs for container %s\n%v", logID, err)
+		summaries <- logSummary{pod: podName, container: logID, err: err}
 		return
 	}
 
-	writeLogToDisk(logs, pod.Name, container.Name, multiPrinter)
-
+	written, err := writeLogToDisk(ctx, logs, podName, logID, np.namespace, multiPrinter, sinks, sinceCutoff)
+	summaries <- logSummary{pod: podName, container: logID, written: written, err: err}
 }
 
-func findPodByLabel(label string) v1.PodList {
-	pterm.Info.Printf("Getting Pods in namespace %s with label %s\n\n", pterm.Green(*namespace), pterm.Green(label))
-
-	pods, err := client.CoreV1().Pods(*namespace).List(context.TODO(), metav1.ListOptions{
-		LabelSelector: label,
-	})
-	if statusError, isStatus := err.(*errors.StatusError); isStatus {
-		fmt.Printf("Error getting pods in namespace %s: %v\n",
-			*namespace, statusError.ErrStatus.Message)
+// resolveSinks builds the set of LogSink destinations a container's log stream is copied to.
+// --no-save overrides everything to stdout-only; otherwise the --sink destination is used, with
+// --stdout additionally mirroring to the terminal.
+func resolveSinks(ctx context.Context) ([]LogSink, error) {
+	if *noSave {
+		return []LogSink{stdoutSink{}}, nil
 	}
+
+	primary, err := newLogSink(ctx, *sink, *logPath)
 	if err != nil {
-		panic(err.Error())
+		return nil, err
 	}
+	sinks := []LogSink{primary}
 
-	// if pods are not found print message
-	if len(pods.Items) == 0 {
-		pterm.Error.Printfln("No pods found in namespace %s with label %s\n", *namespace, label)
+	if *stdout {
+		if _, isStdout := primary.(stdoutSink); !isStdout {
+			sinks = append(sinks, stdoutSink{})
+		}
 	}
-
-	return *pods
+	return sinks, nil
 }
 
-func writeLogToDisk(logs io.ReadCloser, podName string, containerName string, multiPrinter *pterm.MultiPrinter) int {
-	anyLogFound = true
-
-	logName := fmt.Sprintf("%s-%s.log", podName, containerName)
-
-	defer func(logs io.ReadCloser) {
-		err := logs.Close()
-		if err != nil {
-			panic(err.Error())
-		}
-	}(logs)
+func writeLogToDisk(ctx context.Context, logs io.ReadCloser, podName string, containerName string, namespace string, multiPrinter *pterm.MultiPrinter, sinks []LogSink, sinceCutoff time.Time) (written int, err error) {
+	defer logs.Close()
 
 	// Test if logs is empty
 	bufTest := make([]byte, 1)
 	n, err := logs.Read(bufTest)
 	if err != nil && err != io.EOF {
-		panic(err.Error())
+		return 0, err
 	}
 	if n == 0 {
 		// some logs could be empty
-		pterm.Warning.Printfln("Empty logs for %s", logName)
-		return 0
-	}
-
-	// Create the log file
-	if err := os.MkdirAll(*logPath, 0755); err != nil {
-		panic(err.Error())
+		pterm.Warning.Printfln("Empty logs for %s/%s", podName, containerName)
+		return 0, nil
 	}
-	logFilePath := filepath.Join(*logPath, logName)
-	logFile, err := os.Create(logFilePath)
 
-	if err != nil {
-		panic(err.Error())
-	}
-	defer func(logFile *os.File) {
-		err := logFile.Close()
-		if err != nil {
-			panic(err.Error())
+	writers := make([]io.WriteCloser, 0, len(sinks))
+	for _, s := range sinks {
+		w, oerr := s.Open(podName, containerName, namespace)
+		if oerr != nil {
+			return 0, oerr
+		}
+		writers = append(writers, w)
+	}
+	// Sinks like s3Sink/pipeUploadWriter and lokiStreamWriter do their actual upload/flush inside
+	// Close, so a failure there means the log never really landed; fold it into the returned error
+	// instead of silently reporting a successful byte count.
+	defer func() {
+		for _, w := range writers {
+			if cerr := w.Close(); cerr != nil {
+				err = errors.Join(err, fmt.Errorf("closing sink for %s/%s: %w", podName, containerName, cerr))
+			}
 		}
-	}(logFile)
+	}()
 
-	// Write the first byte that was read as a test
-	if _, err := logFile.Write(bufTest); err != nil {
-		panic(err.Error())
-	}
 	spinner1, _ := pterm.DefaultSpinner.WithWriter(multiPrinter.NewWriter()).Start("Streaming logs...")
 	defer spinner1.Stop()
 
-	var written = 1
+	fanOut := fanOutWriter{writers: writers}
+	var lineFilter *timestampFilterWriter
+	if !sinceCutoff.IsZero() {
+		lineFilter = newTimestampFilterWriter(fanOut, sinceCutoff)
+		// Flush the filter's trailing partial line before the sinks it writes into get closed.
+		defer func() {
+			before := lineFilter.Forwarded()
+			if ferr := lineFilter.Close(); ferr != nil {
+				err = errors.Join(err, ferr)
+			}
+			written += lineFilter.Forwarded() - before
+		}()
+	}
+
+	write := func(p []byte) error {
+		if lineFilter != nil {
+			before := lineFilter.Forwarded()
+			_, err := lineFilter.Write(p)
+			written += lineFilter.Forwarded() - before
+			return err
+		}
+		if _, err := fanOut.Write(p); err != nil {
+			return err
+		}
+		written += len(p)
+		return nil
+	}
+
+	if err := write(bufTest); err != nil {
+		return written, err
+	}
 
 	reader := bufio.NewReader(logs)
 	data := make([]byte, 100)
 	for {
+		// Ctrl+C during a follow cancels ctx; stop cleanly instead of blocking on the next read
+		select {
+		case <-ctx.Done():
+			return written, ctx.Err()
+		default:
+		}
+
 		n, err := reader.Read(data)
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			fmt.Println(err)
-			return 0
+			return written, err
 		}
-		// Write the data to the file
-		w, err := logFile.Write(data[:n])
-		if err != nil {
-			panic(err.Error())
+		if err := write(data[:n]); err != nil {
+			return written, err
 		}
-		written += w
+
 		s := pterm.Style{pterm.FgWhite, pterm.BgDefault, pterm.Bold, pterm.Italic}
 
 		spinner1.Text = pterm.Info.WithPrefix(
@@ -355,8 +431,8 @@ func writeLogToDisk(logs io.ReadCloser, podName string, containerName string, mu
 			Sprintf("%s/%s", podName, containerName)
 	}
 
-	// return the number of bytes written in kilobytes
-	return written
+	// return the number of bytes written
+	return written, nil
 }
 
 func convertBytes(bytes int) string {
@@ -378,34 +454,52 @@ var rootCmd = &cobra.Command{
 	Long: `klogs is a CLI tool to get logs from Kubernetes Pods.
 It is designed to be fast and efficient, and can get logs from multiple Pods/Containers at once. Blazing fast. 🔥`,
 
-	Run: func(cmd *cobra.Command, args []string) {
-		var podList v1.PodList
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 
 		splashScreen()
 
-		configClient()
-		configNamespace()
-
-		if len(*labels) == 0 {
-			podList = listAllPods()
-		} else {
-			for _, l := range *labels {
-				podList.Items = append(podList.Items, findPodByLabel(l).Items...)
+		if err := configClient(ctx); err != nil {
+			return err
+		}
+		if !*allNamespaces {
+			if err := configNamespace(ctx); err != nil {
+				return err
 			}
 		}
 
-		getPodLogs(podList, getLopOpts())
+		sel, err := buildPodSelector()
+		if err != nil {
+			return err
+		}
+		pods, err := selectPods(ctx, sel)
+		if err != nil {
+			return err
+		}
+
+		logOpts, sinceCutoff, err := getLopOpts()
+		if err != nil {
+			return err
+		}
+		if err := getPodLogs(ctx, pods, logOpts, sel.ContainerFilter, sinceCutoff); err != nil {
+			return err
+		}
 
 		if anyLogFound {
 			pterm.Info.Printfln("Logs saved to %s", *logPath)
 		}
+		return nil
 	},
 }
 
-// Execute is the entry point for the command
+// Execute is the entry point for the command. It wires Ctrl+C/SIGTERM into a cancellable
+// root context so an in-progress --follow can be stopped cleanly instead of leaking goroutines.
 func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		pterm.Error.Printfln("%v", err)
 		os.Exit(1)
 	}
 }
@@ -414,11 +508,21 @@ func init() {
 	namespace = rootCmd.Flags().StringP("namespace", "n", "", "Select namespace")
 	labels = rootCmd.Flags().StringArrayP("label", "l", []string{}, "Select label")
 	logPath = rootCmd.Flags().StringP("logpath", "p", defaultLogPath, "Custom log path")
-	kubeconfig = rootCmd.Flags().String("kubeconfig", "", "(optional) Absolute path to the kubeconfig file")
+	kubeconfig = rootCmd.PersistentFlags().String("kubeconfig", "", "(optional) Absolute path to the kubeconfig file")
 	allPods = rootCmd.Flags().BoolP("all", "a", false, "Get logs for all pods in the namespace")
 	since = rootCmd.Flags().StringP("since", "s", "", "Only return logs newer than a relative duration like 5s, 2m, or 3h. Defaults to all logs.")
 	tail = rootCmd.Flags().Int64P("tail", "t", -1, "Lines of the most recent log to save")
 	follow = rootCmd.Flags().BoolP("follow", "f", false, "Specify if the logs should be streamed")
+	stdout = rootCmd.Flags().Bool("stdout", false, "Also multiplex logs to stdout, prefixed with pod/container")
+	noSave = rootCmd.Flags().Bool("no-save", false, "Don't save logs to disk, only stream them to stdout")
+	timestamps = rootCmd.Flags().Bool("timestamps", false, "Prepend an RFC3339 timestamp to each log line; combined with --since, filters out lines older than the cutoff with sub-second precision")
+	sink = rootCmd.Flags().String("sink", "file", "Where to send logs: file, file+gzip, stdout, s3://bucket/prefix, gs://bucket/prefix, loki")
+	lokiURL = rootCmd.Flags().String("loki-url", "", "Loki push endpoint (e.g. http://loki:3100/loki/api/v1/push), required when --sink=loki")
+	previous = rootCmd.Flags().BoolP("previous", "P", false, "Also fetch each container's previous (crashed) instance logs, where available")
+	fieldSelector = rootCmd.Flags().String("field-selector", "", "Field selector to filter pods, e.g. status.phase=Running")
+	containerRegex = rootCmd.Flags().String("container", "", "Only fetch logs from containers whose name matches this regex")
+	allNamespaces = rootCmd.Flags().BoolP("all-namespaces", "A", false, "List and fetch logs from pods across all namespaces")
+	readyOnly = rootCmd.Flags().Bool("ready-only", false, "Only consider pods with PodReady=True (pending/crashing pods are included by default)")
 
 	if home := homedir.HomeDir(); home != "" && *kubeconfig == "" {
 		*kubeconfig = filepath.Join(home, ".kube", "config")
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/pterm/pterm"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodSelector describes which pods a run should collect logs from, and how.
+type PodSelector struct {
+	Labels          []string
+	FieldSelector   string
+	AllNamespaces   bool
+	ReadyOnly       bool
+	ContainerFilter *regexp.Regexp
+}
+
+// namespacedPod pairs a pod with the namespace it was listed from, since a plain v1.Pod can't
+// tell --all-namespaces pods apart once they're merged into one list.
+type namespacedPod struct {
+	namespace string
+	pod       v1.Pod
+}
+
+// key identifies the pod in the interactive multiselect and the pod tree: just the pod name in
+// single-namespace mode, "namespace/pod" once --all-namespaces is active.
+func (p namespacedPod) key() string {
+	if !*allNamespaces || p.namespace == "" {
+		return p.pod.Name
+	}
+	return p.namespace + "/" + p.pod.Name
+}
+
+// logPodName is the identifier sinks use to build per-pod file/object names.
+func (p namespacedPod) logPodName() string {
+	if !*allNamespaces || p.namespace == "" {
+		return p.pod.Name
+	}
+	return p.namespace + "-" + p.pod.Name
+}
+
+// buildPodSelector reads the pod-selection flags into a PodSelector.
+func buildPodSelector() (PodSelector, error) {
+	var containerFilter *regexp.Regexp
+	if *containerRegex != "" {
+		re, err := regexp.Compile(*containerRegex)
+		if err != nil {
+			return PodSelector{}, err
+		}
+		containerFilter = re
+	}
+
+	return PodSelector{
+		Labels:          *labels,
+		FieldSelector:   *fieldSelector,
+		AllNamespaces:   *allNamespaces,
+		ReadyOnly:       *readyOnly,
+		ContainerFilter: containerFilter,
+	}, nil
+}
+
+// selectPods resolves sel against the cluster, prompting interactively when no labels narrow
+// the selection down and --all isn't set.
+func selectPods(ctx context.Context, sel PodSelector) ([]namespacedPod, error) {
+	ns := *namespace
+	if sel.AllNamespaces {
+		if ns != "" {
+			pterm.Warning.Printfln("Ignoring --namespace %s because --all-namespaces is set", ns)
+		}
+		ns = ""
+	}
+
+	if len(sel.Labels) == 0 {
+		return listAllPods(ctx, ns, sel)
+	}
+
+	var all []namespacedPod
+	for _, l := range sel.Labels {
+		pods, err := findPodByLabel(ctx, ns, l, sel)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, pods...)
+	}
+	return all, nil
+}
+
+func listAllPods(ctx context.Context, ns string, sel PodSelector) ([]namespacedPod, error) {
+	pods, err := client.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{FieldSelector: sel.FieldSelector})
+	if err != nil {
+		return nil, err
+	}
+
+	podMap := make(map[string]namespacedPod)
+	var keys []string
+	for _, pod := range pods.Items {
+		if sel.ReadyOnly && !isPodReady(pod) {
+			continue
+		}
+		np := namespacedPod{namespace: pod.Namespace, pod: pod}
+		podMap[np.key()] = np
+		keys = append(keys, np.key())
+	}
+
+	if len(keys) == 0 {
+		pterm.Error.Printfln("No pods found in %s", describeNamespaceScope(ns))
+		return nil, nil
+	}
+
+	if !*allPods {
+		keys = showInteractivePodSelect(keys)
+		if len(keys) == 0 {
+			pterm.Error.Printfln("No pods selected")
+			return nil, nil
+		}
+	}
+
+	selected := make([]namespacedPod, 0, len(keys))
+	for _, k := range keys {
+		selected = append(selected, podMap[k])
+	}
+	return selected, nil
+}
+
+func findPodByLabel(ctx context.Context, ns, label string, sel PodSelector) ([]namespacedPod, error) {
+	pterm.Info.Printf("Getting Pods in %s with label %s\n\n", pterm.Green(describeNamespaceScope(ns)), pterm.Green(label))
+
+	pods, err := client.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{
+		LabelSelector: label,
+		FieldSelector: sel.FieldSelector,
+	})
+	if statusError, isStatus := err.(*errors.StatusError); isStatus {
+		pterm.Error.Printfln("Error getting pods in %s: %v", describeNamespaceScope(ns), statusError.ErrStatus.Message)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// if pods are not found print message
+	if len(pods.Items) == 0 {
+		pterm.Error.Printfln("No pods found in %s with label %s\n", describeNamespaceScope(ns), label)
+	}
+
+	var result []namespacedPod
+	for _, pod := range pods.Items {
+		if sel.ReadyOnly && !isPodReady(pod) {
+			continue
+		}
+		result = append(result, namespacedPod{namespace: pod.Namespace, pod: pod})
+	}
+	return result, nil
+}
+
+func isPodReady(pod v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady && condition.Status == v1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func describeNamespaceScope(ns string) string {
+	if ns == "" {
+		return "all namespaces"
+	}
+	return "namespace " + ns
+}
+
+// filterContainers drops containers whose name doesn't match re. A nil re keeps everything.
+func filterContainers(containers []podContainer, re *regexp.Regexp) []podContainer {
+	if re == nil {
+		return containers
+	}
+	var out []podContainer
+	for _, c := range containers {
+		if re.MatchString(c.name) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// LogSink is a destination that receives a container's streamed logs. Open is called once per
+// pod/container and returns a writer that streamLog copies the log stream into. namespace is the
+// pod's own namespace, not a single run-wide value, since --all-namespaces can stream pods from
+// many namespaces in the same run.
+type LogSink interface {
+	Open(pod, container, namespace string) (io.WriteCloser, error)
+}
+
+// newLogSink builds the LogSink named by spec. dir is only used by the file-based sinks.
+func newLogSink(ctx context.Context, spec, dir string) (LogSink, error) {
+	switch {
+	case spec == "" || spec == "file":
+		return &fileSink{dir: dir}, nil
+	case spec == "file+gzip":
+		return &gzipFileSink{dir: dir}, nil
+	case spec == "stdout":
+		return stdoutSink{}, nil
+	case strings.HasPrefix(spec, "s3://"):
+		return newS3Sink(ctx, spec)
+	case strings.HasPrefix(spec, "gs://"):
+		return newGCSSink(ctx, spec)
+	case spec == "loki":
+		if *lokiURL == "" {
+			return nil, fmt.Errorf("--sink=loki requires --loki-url")
+		}
+		return &lokiSink{ctx: ctx, pushURL: *lokiURL}, nil
+	default:
+		return nil, fmt.Errorf("unknown --sink %q", spec)
+	}
+}
+
+// parseObjectURL splits an "s3://bucket/prefix" or "gs://bucket/prefix" spec into its parts.
+func parseObjectURL(rawURL string) (bucket, prefix string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
+
+// fileSink is the original behavior: one plain .log file per pod/container under dir.
+type fileSink struct {
+	dir string
+}
+
+func (s *fileSink) Open(pod, container, namespace string) (io.WriteCloser, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, err
+	}
+	anyLogFound = true
+	return os.Create(filepath.Join(s.dir, fmt.Sprintf("%s-%s.log", pod, container)))
+}
+
+// gzipFileSink streams through a gzip.Writer so long follows never hold the whole log in memory
+// before it hits disk, producing one <pod>-<container>.log.gz per container.
+type gzipFileSink struct {
+	dir string
+}
+
+func (s *gzipFileSink) Open(pod, container, namespace string) (io.WriteCloser, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(filepath.Join(s.dir, fmt.Sprintf("%s-%s.log.gz", pod, container)))
+	if err != nil {
+		return nil, err
+	}
+	anyLogFound = true
+	return &gzipWriteCloser{file: f, gz: gzip.NewWriter(f)}, nil
+}
+
+type gzipWriteCloser struct {
+	file *os.File
+	gz   *gzip.Writer
+}
+
+func (w *gzipWriteCloser) Write(p []byte) (int, error) {
+	return w.gz.Write(p)
+}
+
+func (w *gzipWriteCloser) Close() error {
+	if err := w.gz.Close(); err != nil {
+		_ = w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// stdoutSink multiplexes every container's log to the terminal through a shared, line-splitting,
+// colour-prefixed writer so concurrent streams can't interleave mid-line.
+type stdoutSink struct{}
+
+func (stdoutSink) Open(pod, container, namespace string) (io.WriteCloser, error) {
+	return newLineSplittingWriter(os.Stdout, stdoutMu, prefixFor(pod, container)), nil
+}
+
+// pipeUploadWriter streams writes into an io.Pipe consumed by a background object-storage upload,
+// so a long --follow doesn't buffer the whole log in memory before it can be shipped.
+type pipeUploadWriter struct {
+	pw   *io.PipeWriter
+	done <-chan error
+}
+
+func (w *pipeUploadWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+func (w *pipeUploadWriter) Close() error {
+	if err := w.pw.Close(); err != nil {
+		return err
+	}
+	return <-w.done
+}
+
+// s3Sink uploads each container's log as a multipart object under s3://bucket/prefix.
+type s3Sink struct {
+	ctx    context.Context
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func newS3Sink(ctx context.Context, rawURL string) (*s3Sink, error) {
+	bucket, prefix, err := parseObjectURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Sink{ctx: ctx, client: s3.NewFromConfig(cfg), bucket: bucket, prefix: prefix}, nil
+}
+
+func (s *s3Sink) Open(pod, container, namespace string) (io.WriteCloser, error) {
+	key := path.Join(s.prefix, fmt.Sprintf("%s-%s.log", pod, container))
+	pr, pw := io.Pipe()
+	uploader := manager.NewUploader(s.client)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := uploader.Upload(s.ctx, &s3.PutObjectInput{
+			Bucket: &s.bucket,
+			Key:    &key,
+			Body:   pr,
+		})
+		_ = pr.CloseWithError(err)
+		done <- err
+	}()
+
+	return &pipeUploadWriter{pw: pw, done: done}, nil
+}
+
+// gcsSink uploads each container's log under gs://bucket/prefix. storage.Writer already streams
+// to GCS as it's written, so no extra buffering is needed here.
+type gcsSink struct {
+	ctx    context.Context
+	client *storage.Client
+	bucket string
+	prefix string
+}
+
+func newGCSSink(ctx context.Context, rawURL string) (*gcsSink, error) {
+	bucket, prefix, err := parseObjectURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsSink{ctx: ctx, client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+func (s *gcsSink) Open(pod, container, namespace string) (io.WriteCloser, error) {
+	key := path.Join(s.prefix, fmt.Sprintf("%s-%s.log", pod, container))
+	return s.client.Bucket(s.bucket).Object(key).NewWriter(s.ctx), nil
+}
+
+const lokiBatchSize = 100
+
+// lokiSink batches log lines per container and pushes them to Loki's /loki/api/v1/push endpoint.
+type lokiSink struct {
+	ctx     context.Context
+	pushURL string
+}
+
+func (s *lokiSink) Open(pod, container, namespace string) (io.WriteCloser, error) {
+	return &lokiStreamWriter{
+		ctx:     s.ctx,
+		pushURL: s.pushURL,
+		labels: map[string]string{
+			"pod":       pod,
+			"container": container,
+			"namespace": namespace,
+		},
+	}, nil
+}
+
+type lokiStreamWriter struct {
+	ctx     context.Context
+	pushURL string
+	labels  map[string]string
+	buf     bytes.Buffer
+	batch   [][2]string
+}
+
+func (w *lokiStreamWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// no complete line left; put the partial line back and wait for more data
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			break
+		}
+		w.batch = append(w.batch, [2]string{lokiTimestamp(), strings.TrimSuffix(line, "\n")})
+		if len(w.batch) >= lokiBatchSize {
+			if err := w.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(p), nil
+}
+
+func (w *lokiStreamWriter) Close() error {
+	if w.buf.Len() > 0 {
+		w.batch = append(w.batch, [2]string{lokiTimestamp(), w.buf.String()})
+		w.buf.Reset()
+	}
+	return w.flush()
+}
+
+func (w *lokiStreamWriter) flush() error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+
+	payload := lokiPushRequest{Streams: []lokiStream{{Stream: w.labels, Values: w.batch}}}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, w.pushURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("loki push to %s failed: %s", w.pushURL, resp.Status)
+	}
+
+	w.batch = nil
+	return nil
+}
+
+func lokiTimestamp() string {
+	return fmt.Sprintf("%d", time.Now().UnixNano())
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type lokiPushRequest struct {
+	Streams []lokiStream `json:"streams"`
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+// stdoutMu serializes writes to os.Stdout across the goroutines streaming each container's logs,
+// so concurrent streams can't interleave mid-line.
+var stdoutMu = &sync.Mutex{}
+
+var prefixColors = []pterm.Color{
+	pterm.FgCyan, pterm.FgMagenta, pterm.FgYellow, pterm.FgGreen,
+	pterm.FgBlue, pterm.FgRed, pterm.FgLightCyan, pterm.FgLightMagenta,
+	pterm.FgLightYellow, pterm.FgLightGreen, pterm.FgLightBlue, pterm.FgLightRed,
+}
+
+// colorFor picks a stable color for key, so the same pod/container always gets the same prefix color.
+func colorFor(key string) pterm.Color {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return prefixColors[h.Sum32()%uint32(len(prefixColors))]
+}
+
+// prefixFor renders the "podName/containerName |" prefix used by the stdout multiplexer.
+func prefixFor(podName, containerName string) string {
+	key := podName + "/" + containerName
+	return colorFor(key).Sprintf("%s |", key)
+}
+
+// lineSplittingWriter buffers partial writes until a newline is seen, then writes each complete
+// line to the shared underlying writer under mu, prefixed with prefix. This keeps concurrent
+// streams from interleaving mid-line when several containers multiplex to the same writer.
+type lineSplittingWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    bytes.Buffer
+}
+
+func newLineSplittingWriter(out io.Writer, mu *sync.Mutex, prefix string) *lineSplittingWriter {
+	return &lineSplittingWriter{mu: mu, out: out, prefix: prefix}
+}
+
+func (w *lineSplittingWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// no complete line left; put the partial line back and wait for more data
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			break
+		}
+		w.writeLine(strings.TrimSuffix(line, "\n"))
+	}
+	return len(p), nil
+}
+
+// Close flushes any buffered partial line that never saw a trailing newline.
+func (w *lineSplittingWriter) Close() error {
+	if w.buf.Len() > 0 {
+		w.writeLine(w.buf.String())
+		w.buf.Reset()
+	}
+	return nil
+}
+
+func (w *lineSplittingWriter) writeLine(line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fmt.Fprintf(w.out, "%s %s\n", w.prefix, line)
+}
+
+// fanOutWriter copies every write to each of writers, in order, stopping at the first error.
+type fanOutWriter struct {
+	writers []io.WriteCloser
+}
+
+func (f fanOutWriter) Write(p []byte) (int, error) {
+	for _, w := range f.writers {
+		if _, err := w.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// parseLeadingTimestamp extracts the RFC3339Nano timestamp Kubernetes prepends to each log line
+// when PodLogOptions.Timestamps is set, so callers can filter or sort on it.
+func parseLeadingTimestamp(line string) (time.Time, bool) {
+	token := line
+	if i := strings.IndexByte(line, ' '); i >= 0 {
+		token = line[:i]
+	}
+	ts, err := time.Parse(time.RFC3339Nano, token)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return ts, true
+}
+
+// timestampFilterWriter drops log lines whose leading timestamp token is older than cutoff,
+// giving --since sub-second precision that PodLogOptions.SinceSeconds can't express on its own.
+// Lines without a parseable leading timestamp are passed through unfiltered.
+type timestampFilterWriter struct {
+	out       io.Writer
+	cutoff    time.Time
+	buf       bytes.Buffer
+	forwarded int
+}
+
+func newTimestampFilterWriter(out io.Writer, cutoff time.Time) *timestampFilterWriter {
+	return &timestampFilterWriter{out: out, cutoff: cutoff}
+}
+
+func (w *timestampFilterWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// no complete line left; put the partial line back and wait for more data
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			break
+		}
+		if err := w.writeLine(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close flushes any buffered partial line that never saw a trailing newline.
+func (w *timestampFilterWriter) Close() error {
+	if w.buf.Len() > 0 {
+		defer w.buf.Reset()
+		return w.writeLine(w.buf.String())
+	}
+	return nil
+}
+
+func (w *timestampFilterWriter) writeLine(line string) error {
+	if ts, ok := parseLeadingTimestamp(line); ok && ts.Before(w.cutoff) {
+		return nil
+	}
+	n, err := io.WriteString(w.out, line)
+	w.forwarded += n
+	return err
+}
+
+// Forwarded returns the number of bytes actually written to out so far, i.e. excluding lines
+// dropped by the --since cutoff. Callers reporting "bytes written" should use this instead of
+// the size of what was fed into Write, which counts lines before filtering.
+func (w *timestampFilterWriter) Forwarded() int {
+	return w.forwarded
+}